@@ -0,0 +1,240 @@
+// car_export.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	bserv "github.com/ipfs/boxo/blockservice"
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/coreiface/options"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/files"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	ihelper "github.com/ipfs/boxo/ipld/unixfs/importer/helper"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	rpc "github.com/ipfs/kubo/client/rpc"
+	carv2bs "github.com/ipld/go-car/v2/blockstore"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// 默认每个 CAR 分片的目标大小；超过这个体量的集合会被拆成多个分片。
+const defaultCARShardBytes int64 = 100 * 1024 * 1024
+
+// CARExportConfig 控制 --export-car 的分片行为。
+type CARExportConfig struct {
+	ShardSizeBytes int64 // 0 使用 defaultCARShardBytes
+}
+
+// carShardResult 记录单个分片 CAR 文件的产出。
+type carShardResult struct {
+	Path  string
+	Root  cid.Cid
+	Files []string // 这个分片打包的原始文件路径，供 VerifyCARRoot 按同样的子集在线重算
+}
+
+// ExportCAR 把 srcPath（文件或目录）离线打成一份或多份 CARv2，写到 outDir/<name>[_partN].car，
+// 不依赖运行中的 Kubo 守护进程。srcPath 为目录时，按 cfg.ShardSizeBytes 把子文件分箱打包，
+// 超出单个分片大小的集合会产生多个 CAR，每个分片有自己的根 CID。
+func ExportCAR(ctx context.Context, srcPath, outDir, name string, cfg CARExportConfig) ([]carShardResult, error) {
+	shardSize := cfg.ShardSizeBytes
+	if shardSize <= 0 {
+		shardSize = defaultCARShardBytes
+	}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("❌ 创建 CAR 输出目录失败: %w", err)
+	}
+
+	stat, err := os.Stat(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 无法访问路径: %w", err)
+	}
+
+	if !stat.IsDir() {
+		shard, err := writeCARShard(ctx, outDir, name, []string{srcPath})
+		if err != nil {
+			return nil, err
+		}
+		shard.Files = []string{srcPath}
+		return []carShardResult{shard}, nil
+	}
+
+	entries, err := os.ReadDir(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 读取目录失败: %w", err)
+	}
+
+	// 先只做分箱（不落盘），这样才知道最终会产出几个分片，
+	// 再决定文件名要不要带 _partN 后缀——常见的单分片场景应该落盘成 <name>.car。
+	var buckets [][]string
+	var bucket []string
+	var bucketSize int64
+
+	flushBucket := func() {
+		if len(bucket) == 0 {
+			return
+		}
+		buckets = append(buckets, bucket)
+		bucket = nil
+		bucketSize = 0
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("❌ 读取文件信息失败: %w", err)
+		}
+		if bucketSize > 0 && bucketSize+info.Size() > shardSize {
+			flushBucket()
+		}
+		bucket = append(bucket, filepath.Join(srcPath, entry.Name()))
+		bucketSize += info.Size()
+	}
+	flushBucket()
+
+	var shards []carShardResult
+	for i, b := range buckets {
+		shardName := name
+		if len(buckets) > 1 {
+			shardName = fmt.Sprintf("%s_part%d", name, i+1)
+		}
+		shard, err := writeCARShard(ctx, outDir, shardName, b)
+		if err != nil {
+			return nil, err
+		}
+		shard.Files = b
+		shards = append(shards, shard)
+	}
+
+	if len(shards) > 1 {
+		fmt.Printf("⚠️  集合超过单个 CAR 分片大小 (%d bytes)，已拆分为 %d 个分片，每个分片有独立的根 CID\n", shardSize, len(shards))
+	}
+	return shards, nil
+}
+
+// writeCARShard 把 paths 指向的文件打包进 name.car：逐个文件用 balanced layout
+// 构建 UnixFS DAG（chunker 固定 256KiB、CID version 1、raw leaves，与 Kubo 在 --cid-version=1 下的行为一致），
+// 再用一个 UnixFS 目录节点把它们包起来，目录节点的 CID 就是这个分片的根 CID。
+func writeCARShard(ctx context.Context, outDir, name string, paths []string) (carShardResult, error) {
+	carPath := filepath.Join(outDir, name+".car")
+
+	bs, err := carv2bs.OpenReadWrite(carPath, nil, carv2bs.UseWholeCIDs(true))
+	if err != nil {
+		return carShardResult{}, fmt.Errorf("❌ 创建 CAR 文件失败: %w", err)
+	}
+
+	blockService := bserv.New(bs, offline.Exchange(bs))
+	dagServ := dag.NewDAGService(blockService)
+
+	dirNode := uio.NewDirectory(dagServ)
+	dirNode.SetCidBuilder(cid.V1Builder{Codec: cid.DagProtobuf, MhType: mh.SHA2_256})
+
+	for _, p := range paths {
+		node, err := addFileToDAG(ctx, dagServ, p)
+		if err != nil {
+			bs.Finalize()
+			return carShardResult{}, err
+		}
+		if err := dirNode.AddChild(ctx, filepath.Base(p), node); err != nil {
+			bs.Finalize()
+			return carShardResult{}, fmt.Errorf("❌ 添加目录项失败: %w", err)
+		}
+	}
+
+	rootNode, err := dirNode.GetNode()
+	if err != nil {
+		bs.Finalize()
+		return carShardResult{}, fmt.Errorf("❌ 生成目录节点失败: %w", err)
+	}
+	if err := dagServ.Add(ctx, rootNode); err != nil {
+		bs.Finalize()
+		return carShardResult{}, fmt.Errorf("❌ 写入目录节点失败: %w", err)
+	}
+
+	if err := bs.Finalize(); err != nil {
+		return carShardResult{}, fmt.Errorf("❌ 落盘 CAR 文件失败: %w", err)
+	}
+
+	fmt.Printf("✅ CAR 分片已生成: %s\n   - 根 CID: %s\n", carPath, rootNode.Cid())
+	return carShardResult{Path: carPath, Root: rootNode.Cid()}, nil
+}
+
+// addFileToDAG 用 256KiB 定长分块 + balanced layout 构建单个文件的 UnixFS DAG，
+// 这与 Kubo 的 --chunker=size-262144 --cid-version=1 行为一致（cid-version=1 默认启用 raw leaves），
+// 这样离线算出来的 CID 才能和daemon 在线跑 ipfs add 得到的 CID 对得上。
+func addFileToDAG(ctx context.Context, dagServ ipld.DAGService, path string) (ipld.Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	spl := chunker.NewSizeSplitter(f, 262144)
+	params := ihelper.DagBuilderParams{
+		Dagserv:    dagServ,
+		RawLeaves:  true,
+		Maxlinks:   ihelper.DefaultLinksPerBlock,
+		CidBuilder: cid.V1Builder{Codec: cid.DagProtobuf, MhType: mh.SHA2_256},
+	}
+	db, err := params.New(spl)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 初始化 DAG builder 失败: %w", err)
+	}
+	node, err := balanced.Layout(db)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 构建 UnixFS DAG 失败: %w", err)
+	}
+	return node, nil
+}
+
+// VerifyCARRoot 在有可用的 Kubo 守护进程时，用 options.Unixfs.HashOnly 跑一次
+// “只算 CID、不真正写入”的 ipfs add，核对离线算出来的 root 是否与在线结果一致。
+// paths 必须是 writeCARShard 打包进同一个分片的那组文件（carShardResult.Files）：
+// 离线侧总是把 paths 包进一个 UnixFS 目录节点再算根 CID（哪怕只有一个文件），
+// 所以这里也要用同一组文件构造一个目录节点在线重算，而不是对 srcPath 之类的
+// 完整来源路径整体重算——否则多分片导出时，每个分片的根必然对不上整批源目录。
+// 没有可用守护进程时直接跳过，不视为错误——这本来就是离线工作流的设计初衷。
+func VerifyCARRoot(ctx context.Context, apiURL string, paths []string, offlineRoot cid.Cid) error {
+	shell, err := rpc.NewURLApiWithClient(apiURL, http.DefaultClient)
+	if err != nil {
+		fmt.Printf("ℹ️  未检测到可用的 Kubo 守护进程，跳过在线校验: %v\n", err)
+		return nil
+	}
+
+	entries := make(map[string]files.Node, len(paths))
+	for _, p := range paths {
+		stat, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("❌ 无法访问路径: %w", err)
+		}
+		file, err := files.NewSerialFile(p, false, stat)
+		if err != nil {
+			return fmt.Errorf("❌ 创建 IPFS 文件节点失败: %w", err)
+		}
+		entries[filepath.Base(p)] = file
+	}
+	dirNode := files.NewMapDirectory(entries)
+
+	cidPath, err := shell.Unixfs().Add(ctx, dirNode, options.Unixfs.CidVersion(1), options.Unixfs.HashOnly(true))
+	if err != nil {
+		fmt.Printf("ℹ️  在线校验失败，跳过: %v\n", err)
+		return nil
+	}
+
+	onlineRoot := cidPath.Root().String()
+	if onlineRoot != offlineRoot.String() {
+		return fmt.Errorf("❌ 离线计算的根 CID (%s) 与 ipfs add --only-hash 得到的 (%s) 不一致", offlineRoot, onlineRoot)
+	}
+	fmt.Printf("✅ 离线 CID 与在线 ipfs add --only-hash 结果一致: %s\n", offlineRoot)
+	return nil
+}
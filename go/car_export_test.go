@@ -0,0 +1,79 @@
+// car_export_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name string, size int) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	return path
+}
+
+func TestExportCAR_SingleShard_PlainName(t *testing.T) {
+	srcDir := t.TempDir()
+	outDir := t.TempDir()
+	writeTestFile(t, srcDir, "1.png", 100)
+	writeTestFile(t, srcDir, "2.png", 100)
+
+	shards, err := ExportCAR(context.Background(), srcDir, outDir, "images", CARExportConfig{ShardSizeBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatalf("ExportCAR 失败: %v", err)
+	}
+	if len(shards) != 1 {
+		t.Fatalf("len(shards) = %d，期望 1（总体积远小于分片大小）", len(shards))
+	}
+	if got := filepath.Base(shards[0].Path); got != "images.car" {
+		t.Errorf("单分片导出文件名 = %q，期望 %q（不应该带 _partN 后缀）", got, "images.car")
+	}
+	if len(shards[0].Files) != 2 {
+		t.Errorf("shard.Files 长度 = %d，期望 2", len(shards[0].Files))
+	}
+}
+
+func TestExportCAR_MultiShard_PartSuffixAndDisjointFiles(t *testing.T) {
+	srcDir := t.TempDir()
+	outDir := t.TempDir()
+	// 每个文件都超过分片大小的一半（700/1000），强制每个分片只能装一个文件。
+	writeTestFile(t, srcDir, "1.png", 700)
+	writeTestFile(t, srcDir, "2.png", 700)
+	writeTestFile(t, srcDir, "3.png", 700)
+
+	shards, err := ExportCAR(context.Background(), srcDir, outDir, "images", CARExportConfig{ShardSizeBytes: 1000})
+	if err != nil {
+		t.Fatalf("ExportCAR 失败: %v", err)
+	}
+	if len(shards) != 3 {
+		t.Fatalf("len(shards) = %d，期望 3（每个文件都超过剩余分片容量）", len(shards))
+	}
+
+	seenFiles := map[string]bool{}
+	seenRoots := map[string]bool{}
+	for i, shard := range shards {
+		wantName := filepath.Join(outDir, "images_part"+strconv.Itoa(i+1)+".car")
+		if shard.Path != wantName {
+			t.Errorf("分片 %d 路径 = %q，期望 %q", i, shard.Path, wantName)
+		}
+		if len(shard.Files) != 1 {
+			t.Errorf("分片 %d 的 Files 长度 = %d，期望 1", i, len(shard.Files))
+		}
+		for _, f := range shard.Files {
+			if seenFiles[f] {
+				t.Errorf("文件 %q 被分到了不止一个分片里", f)
+			}
+			seenFiles[f] = true
+		}
+		seenRoots[shard.Root.String()] = true
+	}
+	if len(seenRoots) != len(shards) {
+		t.Errorf("每个分片应该有独立的根 CID，实际只有 %d 个不同的根", len(seenRoots))
+	}
+}
@@ -0,0 +1,245 @@
+// cluster.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ClusterConfig 描述如何连接一个 ipfs-cluster REST API 以及期望的复制因子。
+type ClusterConfig struct {
+	APIURL          string   `json:"api_url"`         // 例如 http://localhost:9094
+	ReplicationMin  int      `json:"replication_min"` // -1 表示“尽可能多”
+	ReplicationMax  int      `json:"replication_max"`
+	PinName         string   `json:"pin_name"`         // 留空则用上传路径的 basename
+	UserAllocations []string `json:"user_allocations"` // 指定必须持有该 pin 的 peer ID
+}
+
+// ClusterUploader 把内容通过 ipfs-cluster 的 /add 端点发布，
+// 让 processBatchCollection 可以在多个节点间保证冗余，而不是事后依赖 Pinata。
+type ClusterUploader struct {
+	cfg    ClusterConfig
+	client *http.Client
+}
+
+// NewClusterUploader 校验 cfg.APIURL 已配置并返回一个 ClusterUploader。
+func NewClusterUploader(cfg ClusterConfig) (*ClusterUploader, error) {
+	if cfg.APIURL == "" {
+		return nil, fmt.Errorf("❌ 缺少 IPFS Cluster 配置: cluster.api_url 未设置")
+	}
+	if cfg.ReplicationMin == 0 {
+		cfg.ReplicationMin = -1
+	}
+	if cfg.ReplicationMax == 0 {
+		cfg.ReplicationMax = -1
+	}
+	return &ClusterUploader{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (c *ClusterUploader) Name() string {
+	return string(BackendCluster)
+}
+
+// clusterAddResult 对应 /add 返回的 ndjson 流中的每一行。
+type clusterAddResult struct {
+	Name string `json:"Name"`
+	Cid  struct {
+		Cid string `json:"/"`
+	} `json:"Cid"`
+}
+
+// UploadPath 把 targetPath 以 cid-version=1 的方式流式上传到 cluster，逐行解析 ndjson 响应。
+// 只有目录才会额外带上 wrap-with-directory：单个文件不需要被包一层目录，
+// 否则根 CID 会变成那层包装目录，而不是文件本身。
+func (c *ClusterUploader) UploadPath(ctx context.Context, targetPath string, onProgress ProgressFunc) (string, error) {
+	fmt.Printf("\n--- 正在上传到 IPFS Cluster: %s ---\n", targetPath)
+
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 无法访问路径: %w", err)
+	}
+	wrapped := stat.IsDir()
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		err := streamMultipartDir(writer, targetPath, stat, onProgress)
+		writer.Close()
+		pw.CloseWithError(err)
+	}()
+
+	reqURL := c.buildAddURL(targetPath, wrapped)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, pr)
+	if err != nil {
+		return "", fmt.Errorf("❌ 构造 cluster /add 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("❌ 上传到 IPFS Cluster 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("❌ cluster /add 返回 status=%d: %s", resp.StatusCode, string(body))
+	}
+
+	var results []clusterAddResult
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var result clusterAddResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return "", fmt.Errorf("❌ 解析 cluster /add 响应失败: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("❌ 读取 cluster /add 响应失败: %w", err)
+	}
+
+	rootCid := pickClusterRootCid(results, targetPath, wrapped)
+	if rootCid == "" {
+		return "", fmt.Errorf("❌ cluster /add 未返回任何 CID")
+	}
+
+	fmt.Println("✅ 上传成功!")
+	fmt.Printf("   - CID: %s\n", rootCid)
+	fmt.Printf("   - 复制因子: min=%d max=%d\n", c.cfg.ReplicationMin, c.cfg.ReplicationMax)
+	return rootCid, nil
+}
+
+// UploadJSON 把元数据先写到临时文件再复用 UploadPath，保持与目录上传相同的 /add 语义。
+func (c *ClusterUploader) UploadJSON(ctx context.Context, data NftMetadata) (string, error) {
+	tmp, err := os.CreateTemp("", "nft-metadata-*.json")
+	if err != nil {
+		return "", fmt.Errorf("❌ 创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("❌ 转换 JSON 失败: %w", err)
+	}
+	if _, err := tmp.Write(jsonData); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("❌ 写入临时文件失败: %w", err)
+	}
+	tmp.Close()
+
+	return c.UploadPath(ctx, tmp.Name(), nil)
+}
+
+// pickClusterRootCid 从 ndjson 结果里挑出真正的根 CID：
+// 没有 wrap 时，响应里就只有目标文件自己那一行（Name 等于其 basename）；
+// wrap 了目录时，根是 Name 为空字符串的那条包装目录记录。
+func pickClusterRootCid(results []clusterAddResult, targetPath string, wrapped bool) string {
+	if wrapped {
+		for _, r := range results {
+			if r.Name == "" {
+				return r.Cid.Cid
+			}
+		}
+		return ""
+	}
+	base := filepath.Base(targetPath)
+	for _, r := range results {
+		if r.Name == base {
+			return r.Cid.Cid
+		}
+	}
+	return ""
+}
+
+func (c *ClusterUploader) buildAddURL(targetPath string, wrapped bool) string {
+	q := url.Values{}
+	if wrapped {
+		q.Set("wrap-with-directory", "true")
+	}
+	q.Set("cid-version", "1")
+	q.Set("replication-min", strconv.Itoa(c.cfg.ReplicationMin))
+	q.Set("replication-max", strconv.Itoa(c.cfg.ReplicationMax))
+	q.Set("stream-channels", "true")
+
+	name := c.cfg.PinName
+	if name == "" {
+		name = filepath.Base(targetPath)
+	}
+	q.Set("name", name)
+
+	for _, peer := range c.cfg.UserAllocations {
+		q.Add("user-allocations", peer)
+	}
+
+	return c.cfg.APIURL + "/add?" + q.Encode()
+}
+
+// streamMultipartDir 把 path（文件或目录）写入 multipart writer，
+// 字段形状与 Kubo 的 /api/v0/add 一致，这样 cluster 能按相同方式重建目录结构。
+// 每个文件是一个 "file" part，filepath 放在 part 的 filename 参数里，不另写 filepath 表单字段；
+// 目录内文件的 filepath 只取相对 path 自身的相对路径（不带 path 自己的目录名前缀）：
+// UploadPath 已经用 wrap-with-directory 把这些文件包进一层新目录来产出单个根 CID，
+// 如果 filepath 再带上 path 的目录名前缀，就会在 wrap 出来的目录下面多出一层同名子目录，
+// 根 CID 的直接子节点就变成那层目录而不是文件本身，和 Kubo 后端的寻址方式不一致。
+func streamMultipartDir(writer *multipart.Writer, path string, stat os.FileInfo, onProgress ProgressFunc) error {
+	addFile := func(fsPath, archivePath string) error {
+		f, err := os.Open(fsPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		part, err := writer.CreateFormFile("file", archivePath)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f); err != nil {
+			return err
+		}
+		if onProgress != nil {
+			onProgress(archivePath, info.Size(), info.Size())
+		}
+		return nil
+	}
+
+	if !stat.IsDir() {
+		return addFile(path, filepath.Base(path))
+	}
+
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		return addFile(p, rel)
+	})
+}
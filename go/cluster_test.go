@@ -0,0 +1,149 @@
+// cluster_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeClusterAddServer 模拟 ipfs-cluster 的 /add 端点：记录收到的 query 参数和
+// 每个 "file" part 在 Content-Disposition 里的原始 filename（即文件的 filepath），
+// 再按 lines 回放固定的 ndjson 响应。
+func fakeClusterAddServer(t *testing.T, lines []string, gotWrap *string, gotFilepaths *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotWrap = r.URL.Query().Get("wrap-with-directory")
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("解析 multipart 请求失败: %v", err)
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("读取 multipart part 失败: %v", err)
+			}
+			// 不用 part.FileName()：它会做 filepath.Base，把目录前缀丢掉。
+			_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+			if err == nil && part.FormName() == "file" {
+				*gotFilepaths = append(*gotFilepaths, params["filename"])
+			}
+			io.Copy(io.Discard, part)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}))
+}
+
+func TestClusterUploadPath_SingleFile_NoWrap(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "1-*.png")
+	if err != nil {
+		t.Fatalf("创建临时文件失败: %v", err)
+	}
+	tmpFile.WriteString("fake image bytes")
+	tmpFile.Close()
+
+	base := filepath.Base(tmpFile.Name())
+	var gotWrap string
+	var gotFilepaths []string
+	lines := []string{
+		fmt.Sprintf(`{"Name":%q,"Cid":{"/":"bafy-file-cid"}}`, base),
+	}
+	server := fakeClusterAddServer(t, lines, &gotWrap, &gotFilepaths)
+	defer server.Close()
+
+	c := &ClusterUploader{cfg: ClusterConfig{APIURL: server.URL, ReplicationMin: -1, ReplicationMax: -1}, client: server.Client()}
+
+	rootCid, err := c.UploadPath(context.Background(), tmpFile.Name(), nil)
+	if err != nil {
+		t.Fatalf("UploadPath 失败: %v", err)
+	}
+	if gotWrap != "" {
+		t.Errorf("单文件上传不应该设置 wrap-with-directory，实际 query 值: %q", gotWrap)
+	}
+	if rootCid != "bafy-file-cid" {
+		t.Errorf("根 CID = %q，期望 %q（文件自身的 CID，而不是包装目录的 CID）", rootCid, "bafy-file-cid")
+	}
+}
+
+func TestClusterUploadPath_Directory_WrapsAndPicksWrapperCid(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1.png", "2.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("fake"), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	var gotWrap string
+	var gotFilepaths []string
+	lines := []string{
+		`{"Name":"1.png","Cid":{"/":"bafy-child-1"}}`,
+		`{"Name":"2.png","Cid":{"/":"bafy-child-2"}}`,
+		`{"Name":"","Cid":{"/":"bafy-wrapper-root"}}`,
+	}
+	server := fakeClusterAddServer(t, lines, &gotWrap, &gotFilepaths)
+	defer server.Close()
+
+	c := &ClusterUploader{cfg: ClusterConfig{APIURL: server.URL, ReplicationMin: -1, ReplicationMax: -1}, client: server.Client()}
+
+	rootCid, err := c.UploadPath(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatalf("UploadPath 失败: %v", err)
+	}
+	if gotWrap != "true" {
+		t.Errorf("目录上传应该设置 wrap-with-directory=true，实际 query 值: %q", gotWrap)
+	}
+	if rootCid != "bafy-wrapper-root" {
+		t.Errorf("根 CID = %q，期望 %q（包装目录的 CID）", rootCid, "bafy-wrapper-root")
+	}
+
+	// 回归校验：目录内文件的 filepath 字段必须是相对 dir 自身的路径（如 "1.png"），
+	// 不能带上 dir 自己的目录名前缀（如 "images/1.png"）——否则 wrap 出来的目录下面
+	// 会多出一层同名子目录，根 CID 的直接子节点就不是文件本身了。
+	want := map[string]bool{"1.png": true, "2.png": true}
+	if len(gotFilepaths) != len(want) {
+		t.Fatalf("收到的 filepath 字段数量 = %d，期望 %d: %v", len(gotFilepaths), len(want), gotFilepaths)
+	}
+	for _, fp := range gotFilepaths {
+		if !want[fp] {
+			t.Errorf("filepath 字段 = %q，不应该带目录名前缀", fp)
+		}
+	}
+}
+
+func TestPickClusterRootCid(t *testing.T) {
+	results := []clusterAddResult{
+		{Name: "1.png", Cid: struct {
+			Cid string `json:"/"`
+		}{Cid: "bafy-child-1"}},
+		{Name: "", Cid: struct {
+			Cid string `json:"/"`
+		}{Cid: "bafy-wrapper-root"}},
+	}
+
+	if got := pickClusterRootCid(results, "/tmp/images", true); got != "bafy-wrapper-root" {
+		t.Errorf("wrapped=true 时应该返回 Name 为空的那条记录，got %q", got)
+	}
+
+	unwrapped := []clusterAddResult{
+		{Name: "1.png", Cid: struct {
+			Cid string `json:"/"`
+		}{Cid: "bafy-file-cid"}},
+	}
+	if got := pickClusterRootCid(unwrapped, "/tmp/images/1.png", false); got != "bafy-file-cid" {
+		t.Errorf("wrapped=false 时应该按 basename 匹配，got %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+// config.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config 描述本次运行要使用的 Pinning 后端及其参数。
+// 既可以从 config.json 读取，也可以被同名的 CLI flag 覆盖。
+type Config struct {
+	Backend        string               `json:"backend"`      // "kubo" | "pinata" | "cluster"
+	IPFSAPIURL     string               `json:"ipfs_api_url"` // 本地 Kubo 守护进程地址
+	Pinata         PinataConfig         `json:"pinata"`
+	Cluster        ClusterConfig        `json:"cluster"`
+	ImageProcessor ImageProcessorConfig `json:"image_processor"`
+	PrivateNetwork PrivateNetworkConfig `json:"private_network"`
+	HTTPServer     HTTPServerConfig     `json:"http_server"`
+}
+
+// DefaultConfig 返回与历史硬编码常量一致的默认配置（本地 Kubo 节点）。
+func DefaultConfig() *Config {
+	return &Config{
+		Backend:        string(BackendKubo),
+		IPFSAPIURL:     IPFS_API_URL,
+		ImageProcessor: DefaultImageProcessorConfig(),
+		HTTPServer:     DefaultHTTPServerConfig(),
+	}
+}
+
+// LoadConfig 读取 path 指向的 JSON 配置文件并与默认值合并；
+// path 为空字符串时直接返回默认配置。
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 读取配置文件失败: %w", err)
+	}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("❌ 解析配置文件失败: %w", err)
+	}
+	return cfg, nil
+}
+
+func errUnknownBackend(backend string) error {
+	return fmt.Errorf("❌ 未知的 Pinning 后端: %q（可选 kubo/pinata/cluster）", backend)
+}
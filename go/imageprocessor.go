@@ -0,0 +1,181 @@
+// imageprocessor.go
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码器，供 image.Decode 识别源图片格式
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageProcessorConfig 描述上传前的图片优化参数，可随每个集合（config.json）单独调整。
+type ImageProcessorConfig struct {
+	MaxWidth     int    `json:"max_width"`     // 0 表示不限制宽度
+	TargetFormat string `json:"target_format"` // "webp" | "avif" | "jpeg" | "png"；squoosh-cli 不可用时回退到 jpeg
+	Quality      int    `json:"quality"`       // 1-100，有损压缩质量
+	StripEXIF    bool   `json:"strip_exif"`    // 默认 true，避免泄露如 GPS 之类的隐私信息
+}
+
+// DefaultImageProcessorConfig 给出合理默认值：不放大、转 webp、80 质量、剥离 EXIF。
+func DefaultImageProcessorConfig() ImageProcessorConfig {
+	return ImageProcessorConfig{
+		MaxWidth:     0,
+		TargetFormat: "webp",
+		Quality:      80,
+		StripEXIF:    true,
+	}
+}
+
+// ImageProcessor 在上传前对图片做 resize / 格式转换 / 有损压缩，
+// 优先调用 squoosh-cli，不可用时回退到纯 Go 实现。
+type ImageProcessor struct {
+	cfg        ImageProcessorConfig
+	useSquoosh bool
+}
+
+// NewImageProcessor 探测 squoosh-cli 是否存在于 PATH 中，决定走哪条优化路径。
+func NewImageProcessor(cfg ImageProcessorConfig) *ImageProcessor {
+	_, err := exec.LookPath("squoosh-cli")
+	return &ImageProcessor{cfg: cfg, useSquoosh: err == nil}
+}
+
+// Process 优化 srcPath 指向的单张图片，把结果写入 outDir，返回输出路径及优化前后的字节数。
+func (p *ImageProcessor) Process(srcPath, outDir string) (outPath string, originalSize, compressedSize int64, err error) {
+	stat, err := os.Stat(srcPath)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("❌ 无法访问待优化图片: %w", err)
+	}
+	originalSize = stat.Size()
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return "", 0, 0, fmt.Errorf("❌ 创建优化输出目录失败: %w", err)
+	}
+
+	if p.useSquoosh {
+		outPath, err = p.processWithSquoosh(srcPath, outDir)
+	} else {
+		outPath, err = p.processPureGo(srcPath, outDir)
+	}
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	outStat, err := os.Stat(outPath)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("❌ 无法访问优化后图片: %w", err)
+	}
+	compressedSize = outStat.Size()
+	return outPath, originalSize, compressedSize, nil
+}
+
+// squooshCodec 描述 squoosh-cli 某个编码器对应的命令行 flag 和输出文件后缀——
+// 两者不一定相同（比如 jpeg 走 --mozjpeg 但输出后缀是 .jpg），必须配对维护，
+// 否则传给 squoosh-cli 的编码器和 Process 去读取的输出路径就会对不上。
+type squooshCodec struct {
+	flag string
+	ext  string
+}
+
+var squooshCodecsByFormat = map[string]squooshCodec{
+	"webp": {flag: "--webp", ext: "webp"},
+	"avif": {flag: "--avif", ext: "avif"},
+	"jpeg": {flag: "--mozjpeg", ext: "jpg"},
+	"png":  {flag: "--oxipng", ext: "png"},
+}
+
+// processWithSquoosh 调用 squoosh-cli 做 resize + 格式转换，这是质量最好、兼容 webp/avif 的路径。
+func (p *ImageProcessor) processWithSquoosh(srcPath, outDir string) (string, error) {
+	format := p.cfg.TargetFormat
+	if format == "" {
+		format = "webp"
+	}
+	codec, ok := squooshCodecsByFormat[format]
+	if !ok {
+		return "", fmt.Errorf("❌ 不支持的 target_format: %q（可选 webp | avif | jpeg | png）", format)
+	}
+
+	args := []string{"-d", outDir}
+	if format == "png" {
+		// oxipng 是无损压缩，没有 quality 参数
+		args = append(args, codec.flag, "{}")
+	} else {
+		args = append(args, codec.flag, fmt.Sprintf(`{"quality":%d}`, p.cfg.Quality))
+	}
+	if p.cfg.MaxWidth > 0 {
+		args = append(args, "--resize", fmt.Sprintf(`{"enabled":true,"width":%d}`, p.cfg.MaxWidth))
+	}
+	if p.cfg.StripEXIF {
+		args = append(args, "--strip-metadata")
+	}
+	args = append(args, srcPath)
+
+	cmd := exec.Command("squoosh-cli", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("❌ squoosh-cli 执行失败: %w\n%s", err, out)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+	return filepath.Join(outDir, base+"."+codec.ext), nil
+}
+
+// processPureGo 是没有 squoosh-cli 时的退路：用标准库 + x/image/draw 做 resize，
+// 重新编码天然就会剥离 EXIF（标准 decoder 不保留它），所以这条路径上 StripEXIF=true 总能生效；
+// 但标准库也没有办法在重新编码时保留 EXIF，所以 StripEXIF=false 在这条路径上不生效——
+// 这条退路没有 squoosh-cli 的 --strip-metadata 开关，无法像 processWithSquoosh 那样双向控制。
+// x/image 没有 webp/avif 编码器，因此这条路径固定输出 JPEG。
+func (p *ImageProcessor) processPureGo(srcPath, outDir string) (string, error) {
+	if !p.cfg.StripEXIF {
+		fmt.Println("⚠️  未检测到 squoosh-cli，纯 Go 回退路径无法保留 EXIF，仍会剥离")
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 打开图片失败: %w", err)
+	}
+	defer src.Close()
+
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return "", fmt.Errorf("❌ 解码图片失败: %w", err)
+	}
+
+	if p.cfg.MaxWidth > 0 {
+		img = resizeToMaxWidth(img, p.cfg.MaxWidth)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+	outPath := filepath.Join(outDir, base+".jpg")
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 创建优化输出文件失败: %w", err)
+	}
+	defer out.Close()
+
+	quality := p.cfg.Quality
+	if quality <= 0 {
+		quality = 80
+	}
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: quality}); err != nil {
+		return "", fmt.Errorf("❌ 编码优化图片失败: %w", err)
+	}
+	return outPath, nil
+}
+
+func resizeToMaxWidth(src image.Image, maxWidth int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth {
+		return src
+	}
+	newHeight := height * maxWidth / width
+	dst := image.NewRGBA(image.Rect(0, 0, maxWidth, newHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
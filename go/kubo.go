@@ -0,0 +1,85 @@
+// kubo.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	coreiface "github.com/ipfs/boxo/coreiface"
+	"github.com/ipfs/boxo/coreiface/options"
+	"github.com/ipfs/boxo/files"
+	rpc "github.com/ipfs/kubo/client/rpc"
+)
+
+// KuboUploader 把内容添加到一个 Kubo 节点。shell 是 coreiface.CoreAPI，
+// 这样它既可以是官方 RPC 客户端连到的远程/本地守护进程，
+// 也可以是 PrivateNetwork.SpawnEphemeral 启动的 in-process 节点（见 privatenetwork.go）。
+type KuboUploader struct {
+	shell coreiface.CoreAPI
+}
+
+// NewKuboUploader 连接到 apiURL 指向的 Kubo 节点。
+func NewKuboUploader(apiURL string) (*KuboUploader, error) {
+	shell, err := rpc.NewURLApiWithClient(apiURL, http.DefaultClient)
+	if err != nil {
+		return nil, fmt.Errorf("❌ 连接 IPFS 节点失败: %w\n请确保你的 IPFS 节点正在运行 (命令: ipfs daemon)。", err)
+	}
+	return &KuboUploader{shell: shell}, nil
+}
+
+// NewKuboUploaderFromAPI 直接包装一个已经建立好的 coreiface.CoreAPI，
+// 供 in-process 节点这类不经过 HTTP 的场景使用。
+func NewKuboUploaderFromAPI(shell coreiface.CoreAPI) *KuboUploader {
+	return &KuboUploader{shell: shell}
+}
+
+func (k *KuboUploader) Name() string {
+	return string(BackendKubo)
+}
+
+func (k *KuboUploader) UploadPath(ctx context.Context, targetPath string, onProgress ProgressFunc) (string, error) {
+	fmt.Printf("\n--- 正在上传: %s ---\n", targetPath)
+
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 无法访问路径: %w", err)
+	}
+
+	file, err := files.NewSerialFile(targetPath, false, stat)
+	if err != nil {
+		return "", fmt.Errorf("❌ 创建 IPFS 文件节点失败: %w", err)
+	}
+
+	cidPath, err := k.shell.Unixfs().Add(ctx, file, options.Unixfs.Pin(true), options.Unixfs.CidVersion(1))
+	if err != nil {
+		return "", fmt.Errorf("❌ 上传失败: %w", err)
+	}
+
+	cidStr := cidPath.Root().String()
+	if onProgress != nil {
+		onProgress(targetPath, stat.Size(), stat.Size())
+	}
+	fmt.Println("✅ 上传成功!")
+	fmt.Printf("   - CID: %s\n", cidStr)
+	return cidStr, nil
+}
+
+func (k *KuboUploader) UploadJSON(ctx context.Context, data NftMetadata) (string, error) {
+	fmt.Println("\n--- 正在上传 JSON 对象 ---")
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("❌ 转换 JSON 失败: %w", err)
+	}
+
+	cidPath, err := k.shell.Unixfs().Add(ctx, files.NewBytesFile(jsonData), options.Unixfs.Pin(true), options.Unixfs.CidVersion(1))
+	if err != nil {
+		return "", fmt.Errorf("❌ 上传 JSON 失败: %w", err)
+	}
+
+	cidStr := cidPath.Root().String()
+	fmt.Printf("✅ JSON 元数据上传成功!\n   - CID: %s\n", cidStr)
+	return cidStr, nil
+}
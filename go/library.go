@@ -0,0 +1,59 @@
+// library.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SingleNFTResult 是一次"图片 + 元数据"上传的结果，
+// HTTP /upload/single 和 /upload/batch 直接把它序列化成 JSON 返回给调用方。
+type SingleNFTResult struct {
+	ImageCid    string      `json:"imageCid"`
+	MetadataCid string      `json:"metadataCid"`
+	TokenURI    string      `json:"tokenURI"`
+	Metadata    NftMetadata `json:"metadata"`
+}
+
+// UploadSingleNFT 是 processSingleNFT 和 HTTP server 共用的核心逻辑：
+// 可选地优化图片、上传图片、生成并上传元数据。不碰本地磁盘归档（那是 CLI 工作流自己的事）。
+func UploadSingleNFT(ctx context.Context, uploader Uploader, opts WorkflowOptions, imagePath string) (SingleNFTResult, error) {
+	uploadPath := imagePath
+	if opts.Processor != nil {
+		optimizedDir := filepath.Join(filepath.Dir(imagePath), "images_optimized")
+		optimizedPath, _, _, err := opts.Processor.Process(imagePath, optimizedDir)
+		if err != nil {
+			return SingleNFTResult{}, fmt.Errorf("图片优化失败: %w", err)
+		}
+		uploadPath = optimizedPath
+	}
+
+	imageCid, err := uploader.UploadPath(ctx, uploadPath, LogProgress)
+	if err != nil {
+		return SingleNFTResult{}, fmt.Errorf("图片上传失败: %w", err)
+	}
+
+	imageFilename := filepath.Base(imagePath)
+	imageNameWithoutExt := strings.TrimSuffix(imageFilename, filepath.Ext(imageFilename))
+
+	metadata := NftMetadata{
+		Name:        imageNameWithoutExt,
+		Description: fmt.Sprintf("这是一个为图片 %s 动态生成的元数据。", imageFilename),
+		Image:       RewriteGatewayURI(opts.PrivateNet, fmt.Sprintf("ipfs://%s", imageCid)),
+		Attributes:  []Attribute{{TraitType: "类型", Value: "单件艺术品"}},
+	}
+
+	metadataCid, err := uploader.UploadJSON(ctx, metadata)
+	if err != nil {
+		return SingleNFTResult{}, fmt.Errorf("元数据上传失败: %w", err)
+	}
+
+	return SingleNFTResult{
+		ImageCid:    imageCid,
+		MetadataCid: metadataCid,
+		TokenURI:    RewriteGatewayURI(opts.PrivateNet, fmt.Sprintf("ipfs://%s", metadataCid)),
+		Metadata:    metadata,
+	}, nil
+}
@@ -4,23 +4,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
-
-	// ✅ 导入 boxo/files 来处理文件和目录
-	"github.com/ipfs/boxo/files"
-	// ✅ 导入最新的、官方推荐的 Kubo RPC 客户端
-	rpc "github.com/ipfs/kubo/client/rpc"
-	// ✅ 导入最新的、官方推荐的 options 包
-	"github.com/ipfs/boxo/coreiface/options"
 )
 
 // ✅ 配置开关
@@ -41,54 +34,17 @@ type NftMetadata struct {
 	Attributes  []Attribute `json:"attributes"`
 }
 
-// 核心上传函数 (使用官方库)
-func uploadToIPFS(shell *rpc.HttpApi, targetPath string) (string, error) {
-	fmt.Printf("\n--- 正在上传: %s ---\n", targetPath)
-
-	stat, err := os.Stat(targetPath)
-	if err != nil {
-		return "", fmt.Errorf("❌ 无法访问路径: %w", err)
-	}
-
-	file, err := files.NewSerialFile(targetPath, false, stat)
-	if err != nil {
-		return "", fmt.Errorf("❌ 创建 IPFS 文件节点失败: %w", err)
-	}
-
-	// ✅ 使用 Unixfs() API 来添加文件
-	cidPath, err := shell.Unixfs().Add(context.Background(), file, options.Unixfs.Pin(true), options.Unixfs.CidVersion(1))
-	if err != nil {
-		return "", fmt.Errorf("❌ 上传失败: %w", err)
-	}
-
-	cidStr := cidPath.Root().String()
-	fmt.Println("✅ 上传成功!")
-	fmt.Printf("   - 名称: %s\n", filepath.Base(targetPath))
-	fmt.Printf("   - CID: %s\n", cidStr)
-	return cidStr, nil
-}
-
-// 上传 JSON 数据的专用函数
-func uploadJSONToIPFS(shell *rpc.HttpApi, data NftMetadata) (string, error) {
-	fmt.Println("\n--- 正在上传 JSON 对象 ---")
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("❌ 转换 JSON 失败: %w", err)
-	}
-
-	// ✅ 同样使用 Unixfs() API
-	cidPath, err := shell.Unixfs().Add(context.Background(), files.NewBytesFile(jsonData), options.Unixfs.Pin(true), options.Unixfs.CidVersion(1))
-	if err != nil {
-		return "", fmt.Errorf("❌ 上传 JSON 失败: %w", err)
-	}
-
-	cidStr := cidPath.Root().String()
-	fmt.Printf("✅ JSON 元数据上传成功!\n   - CID: %s\n", cidStr)
-	return cidStr, nil
+// WorkflowOptions 收拢两条工作流共用的可选能力开关，
+// 避免 processSingleNFT / processBatchCollection 的参数随需求增长无限变长。
+type WorkflowOptions struct {
+	Processor  *ImageProcessor
+	PrivateNet PrivateNetworkConfig
+	CAR        *CARExportConfig // 非 nil 时额外导出离线 CAR 文件
+	IPFSAPIURL string           // 用于 CAR 导出后的在线校验
 }
 
 // 工作流一：处理单个 NFT
-func processSingleNFT(shell *rpc.HttpApi, imagePath string) {
+func processSingleNFT(uploader Uploader, opts WorkflowOptions, imagePath string) {
 	// ... (此函数内部逻辑无需修改) ...
 	fmt.Println("\n==============================================")
 	fmt.Println("🚀 开始处理单个 NFT...")
@@ -99,27 +55,15 @@ func processSingleNFT(shell *rpc.HttpApi, imagePath string) {
 	}
 	fmt.Println("==============================================")
 
-	imageCid, err := uploadToIPFS(shell, imagePath)
+	result, err := UploadSingleNFT(context.Background(), uploader, opts, imagePath)
 	if err != nil {
-		log.Fatalf("图片上传失败: %v", err)
+		log.Fatalf("%v", err)
 	}
-	fmt.Printf("\n🖼️  图片 CID 已获取: %s\n", imageCid)
+	fmt.Printf("\n🖼️  图片 CID 已获取: %s\n", result.ImageCid)
 
 	imageFilename := filepath.Base(imagePath)
 	imageNameWithoutExt := strings.TrimSuffix(imageFilename, filepath.Ext(imageFilename))
 
-	metadata := NftMetadata{
-		Name:        imageNameWithoutExt,
-		Description: fmt.Sprintf("这是一个为图片 %s 动态生成的元数据。", imageFilename),
-		Image:       fmt.Sprintf("ipfs://%s", imageCid),
-		Attributes:  []Attribute{{TraitType: "类型", Value: "单件艺术品"}},
-	}
-
-	metadataCid, err := uploadJSONToIPFS(shell, metadata)
-	if err != nil {
-		log.Fatalf("元数据上传失败: %v", err)
-	}
-
 	outputDir := filepath.Join("output", imageNameWithoutExt)
 	os.MkdirAll(outputDir, os.ModePerm)
 	copyFile(imagePath, filepath.Join(outputDir, imageFilename))
@@ -129,17 +73,22 @@ func processSingleNFT(shell *rpc.HttpApi, imagePath string) {
 		fileName += ".json"
 	}
 	metadataFile, _ := os.Create(filepath.Join(outputDir, fileName))
-	prettyJSON, _ := json.MarshalIndent(metadata, "", "    ")
+	prettyJSON, _ := json.MarshalIndent(result.Metadata, "", "    ")
 	metadataFile.Write(prettyJSON)
 	metadataFile.Close()
 
 	fmt.Printf("\n💾 图片和元数据已在本地打包保存至: %s\n", outputDir)
+
+	if opts.CAR != nil {
+		exportCAR(context.Background(), outputDir, outputDir, imageNameWithoutExt, *opts.CAR, opts.IPFSAPIURL)
+	}
+
 	fmt.Println("\n--- ✨ 单件流程完成 ✨ ---")
-	fmt.Printf("下一步，您可以在 mint 函数中使用这个元数据 URI: ipfs://%s\n", metadataCid)
+	fmt.Printf("下一步，您可以在 mint 函数中使用这个元数据 URI: %s\n", result.TokenURI)
 }
 
 // 工作流二：处理批量 NFT 集合
-func processBatchCollection(shell *rpc.HttpApi, imagesInputDir string) {
+func processBatchCollection(uploader Uploader, opts WorkflowOptions, imagesInputDir string) {
 	// ... (此函数内部逻辑无需修改) ...
 	fmt.Println("\n==============================================")
 	fmt.Println("🚀 开始处理批量 NFT 集合...")
@@ -150,12 +99,6 @@ func processBatchCollection(shell *rpc.HttpApi, imagesInputDir string) {
 	}
 	fmt.Println("==============================================")
 
-	imagesFolderCid, err := uploadToIPFS(shell, imagesInputDir)
-	if err != nil {
-		log.Fatalf("图片文件夹上传失败: %v", err)
-	}
-	fmt.Printf("\n🖼️  图片文件夹 CID 已获取: %s\n", imagesFolderCid)
-
 	timestamp := time.Now().Format("20060102_150405")
 	collectionOutputDir := filepath.Join("output", fmt.Sprintf("collection_%s", timestamp))
 	imagesOutputDir := filepath.Join(collectionOutputDir, "images")
@@ -164,15 +107,41 @@ func processBatchCollection(shell *rpc.HttpApi, imagesInputDir string) {
 	copyDirectory(imagesInputDir, imagesOutputDir)
 	fmt.Printf("\n💾 所有图片已复制到: %s\n", imagesOutputDir)
 
+	uploadDir := imagesInputDir
+	if opts.Processor != nil {
+		imagesOptimizedDir := filepath.Join(collectionOutputDir, "images_optimized")
+		entries, _ := os.ReadDir(imagesInputDir)
+		var totalOriginal, totalCompressed int64
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			_, originalSize, compressedSize, err := opts.Processor.Process(filepath.Join(imagesInputDir, entry.Name()), imagesOptimizedDir)
+			if err != nil {
+				log.Fatalf("图片优化失败: %v", err)
+			}
+			totalOriginal += originalSize
+			totalCompressed += compressedSize
+		}
+		fmt.Printf("🗜️  %d 张图片优化完成: %d bytes -> %d bytes\n", len(entries), totalOriginal, totalCompressed)
+		uploadDir = imagesOptimizedDir
+	}
+
+	imagesFolderCid, err := uploader.UploadPath(context.Background(), uploadDir, LogProgress)
+	if err != nil {
+		log.Fatalf("图片文件夹上传失败: %v", err)
+	}
+	fmt.Printf("\n🖼️  图片文件夹 CID 已获取: %s\n", imagesFolderCid)
+
 	fmt.Println("\n--- 正在为每张图片生成元数据 JSON 文件 ---")
 	os.MkdirAll(metadataOutputDir, os.ModePerm)
 
-	files, _ := os.ReadDir(imagesInputDir)
+	files, _ := os.ReadDir(uploadDir)
 	var imageFiles []string
 	for _, file := range files {
 		if !file.IsDir() {
 			ext := strings.ToLower(filepath.Ext(file.Name()))
-			if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".gif" {
+			if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".gif" || ext == ".webp" || ext == ".avif" {
 				imageFiles = append(imageFiles, file.Name())
 			}
 		}
@@ -185,7 +154,7 @@ func processBatchCollection(shell *rpc.HttpApi, imagesInputDir string) {
 		metadata := NftMetadata{
 			Name:        fmt.Sprintf("MetaCore #%d", tokenID),
 			Description: "MetaCore 集合中的一个独特成员。",
-			Image:       fmt.Sprintf("ipfs://%s/%s", imagesFolderCid, fileName),
+			Image:       RewriteGatewayURI(opts.PrivateNet, fmt.Sprintf("ipfs://%s/%s", imagesFolderCid, fileName)),
 			Attributes:  []Attribute{{TraitType: "ID", Value: tokenID}},
 		}
 		outFileName := tokenIDStr
@@ -199,24 +168,55 @@ func processBatchCollection(shell *rpc.HttpApi, imagesInputDir string) {
 	}
 	fmt.Printf("✅ 成功生成 %d 个元数据文件到: %s\n", len(imageFiles), metadataOutputDir)
 
-	metadataFolderCid, err := uploadToIPFS(shell, metadataOutputDir)
+	if opts.CAR != nil {
+		exportCAR(context.Background(), uploadDir, collectionOutputDir, "images", *opts.CAR, opts.IPFSAPIURL)
+		exportCAR(context.Background(), metadataOutputDir, collectionOutputDir, "metadata", *opts.CAR, opts.IPFSAPIURL)
+	}
+
+	metadataFolderCid, err := uploader.UploadPath(context.Background(), metadataOutputDir, LogProgress)
 	if err != nil {
 		log.Fatalf("元数据文件夹上传失败: %v", err)
 	}
 	fmt.Printf("\n📄 元数据文件夹 CID 已获取: %s\n", metadataFolderCid)
 	fmt.Println("\n--- ✨ 批量流程完成 ✨ ---")
-	fmt.Printf("下一步，您可以在合约中将 Base URI 设置为: ipfs://%s/\n", metadataFolderCid)
+	fmt.Printf("下一步，您可以在合约中将 Base URI 设置为: %s/\n", RewriteGatewayURI(opts.PrivateNet, fmt.Sprintf("ipfs://%s", metadataFolderCid)))
 }
 
 func main() {
-	// ✅ 使用新的 rpc.NewURLApiWithClient 并提供一个 http client
-	shell, err := rpc.NewURLApiWithClient(IPFS_API_URL, http.DefaultClient)
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServeCommand(os.Args[2:])
+		return
+	}
+
+	configPath := flag.String("config", "", "配置文件路径 (JSON)，用于选择 Pinning 后端及其参数")
+	backendFlag := flag.String("backend", "", "Pinning 后端: kubo | pinata | cluster，覆盖配置文件里的 backend 字段")
+	exportCarFlag := flag.Bool("export-car", false, "额外导出离线 CARv2 文件，支持之后用 ipfs dag import 或直接发给 web3.storage")
+	carShardMB := flag.Int("car-shard-mb", 100, "单个 CAR 分片的目标大小（MiB），超出会被拆成多个分片")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *backendFlag != "" {
+		cfg.Backend = *backendFlag
+	}
+
+	uploader, cleanupUploader, err := NewUploader(cfg)
 	if err != nil {
-		log.Fatalf("❌ 连接 IPFS 节点失败: %v\n请确保你的 IPFS 节点正在运行 (命令: ipfs daemon)。", err)
+		log.Fatalf("%v", err)
+	}
+	defer cleanupUploader()
+	fmt.Printf("✅ 已连接 Pinning 后端: %s\n", uploader.Name())
+
+	opts := WorkflowOptions{
+		Processor:  NewImageProcessor(cfg.ImageProcessor),
+		PrivateNet: cfg.PrivateNetwork,
+		IPFSAPIURL: cfg.IPFSAPIURL,
+	}
+	if *exportCarFlag {
+		opts.CAR = &CARExportConfig{ShardSizeBytes: int64(*carShardMB) * 1024 * 1024}
 	}
-	// ✅ 新库没有 ID() 方法，直接跳过连接检查。
-	// 如果连接有问题，后续的上传操作会自然失败。
-	fmt.Println("✅ 成功连接到 IPFS 节点")
 
 	// 使用 _ 明确忽略未使用的变量，以通过编译器检查
 	singleImagePath := filepath.Join("..", "assets", "image", "IMG_20210626_180340.jpg")
@@ -224,8 +224,8 @@ func main() {
 	os.MkdirAll(batchImagesPath, os.ModePerm)
 
 	// --- 在这里选择要运行的工作流 ---
-	processSingleNFT(shell, singleImagePath)
-	processBatchCollection(shell, batchImagesPath)
+	processSingleNFT(uploader, opts, singleImagePath)
+	processBatchCollection(uploader, opts, batchImagesPath)
 
 	fmt.Println("\n======================================================================")
 	fmt.Println("✅ 本地准备工作已完成！")
@@ -237,6 +237,22 @@ func main() {
 	fmt.Println("======================================================================")
 }
 
+// exportCAR 是 --export-car 的统一入口：导出离线 CARv2 分片，再用 VerifyCARRoot
+// 核对 daemon 可达时在线 ipfs add 产出的 CID 是否与离线算出来的一致。失败只打印警告，
+// 不影响主流程继续往 Pinning 服务上传——CAR 导出本身是"额外产出"，不是关键路径。
+func exportCAR(ctx context.Context, srcPath, outDir, name string, carCfg CARExportConfig, apiURL string) {
+	shards, err := ExportCAR(ctx, srcPath, outDir, name, carCfg)
+	if err != nil {
+		fmt.Printf("⚠️  CAR 导出失败: %v\n", err)
+		return
+	}
+	for _, shard := range shards {
+		if err := VerifyCARRoot(ctx, apiURL, shard.Files, shard.Root); err != nil {
+			fmt.Printf("⚠️  CAR 校验失败: %v\n", err)
+		}
+	}
+}
+
 // --- 辅助函数 ---
 func copyFile(src, dst string) {
 	sourceFile, err := os.Open(src)
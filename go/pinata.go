@@ -0,0 +1,278 @@
+// pinata.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const pinataBaseURL = "https://api.pinata.cloud"
+
+// PinataConfig 描述连接 Pinata pinning 服务所需的凭据与重试参数。
+type PinataConfig struct {
+	APIKey       string `json:"-"` // 来自 PINATA_API_KEY 环境变量
+	SecretAPIKey string `json:"-"` // 来自 PINATA_SECRET_API_KEY 环境变量
+	MaxRetries   int    `json:"max_retries"`
+}
+
+// PinataUploader 把文件/目录和 JSON 元数据上传到 Pinata (pinFileToIPFS / pinJSONToIPFS)。
+type PinataUploader struct {
+	apiKey     string
+	secretKey  string
+	maxRetries int
+	client     *http.Client
+}
+
+// NewPinataUploader 读取 PINATA_API_KEY / PINATA_SECRET_API_KEY 环境变量并做一次鉴权健康检查。
+func NewPinataUploader(cfg PinataConfig) (*PinataUploader, error) {
+	apiKey := os.Getenv("PINATA_API_KEY")
+	secretKey := os.Getenv("PINATA_SECRET_API_KEY")
+	if apiKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("❌ 缺少 Pinata 凭据: 请设置 PINATA_API_KEY / PINATA_SECRET_API_KEY")
+	}
+
+	maxRetries := cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	p := &PinataUploader{
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		maxRetries: maxRetries,
+		client:     &http.Client{Timeout: 60 * time.Second},
+	}
+
+	if err := p.testAuthentication(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *PinataUploader) Name() string {
+	return string(BackendPinata)
+}
+
+func (p *PinataUploader) authHeaders(req *http.Request) {
+	req.Header.Set("pinata_api_key", p.apiKey)
+	req.Header.Set("pinata_secret_api_key", p.secretKey)
+}
+
+// testAuthentication 调 data/testAuthentication 验证凭据有效，在启动时尽早失败。
+func (p *PinataUploader) testAuthentication() error {
+	req, err := http.NewRequest(http.MethodGet, pinataBaseURL+"/data/testAuthentication", nil)
+	if err != nil {
+		return fmt.Errorf("❌ 构造鉴权请求失败: %w", err)
+	}
+	p.authHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("❌ 连接 Pinata 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("❌ Pinata 鉴权失败 (status=%d): %s", resp.StatusCode, string(body))
+	}
+	fmt.Println("✅ Pinata 鉴权成功")
+	return nil
+}
+
+// UploadPath 把 targetPath（文件或目录）通过 pinFileToIPFS 上传，
+// 用 filepath 表单字段保留目录结构，使 ipfs://{CID}/images/1.png 这类路径可用。
+func (p *PinataUploader) UploadPath(ctx context.Context, targetPath string, onProgress ProgressFunc) (string, error) {
+	fmt.Printf("\n--- 正在上传到 Pinata: %s ---\n", targetPath)
+
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 无法访问路径: %w", err)
+	}
+
+	body, contentType, err := buildPinFileBody(targetPath, stat, filepath.Base(targetPath))
+	if err != nil {
+		return "", err
+	}
+
+	var cid string
+	err = withBackoff(p.maxRetries, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, pinataBaseURL+"/pinning/pinFileToIPFS", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		p.authHeaders(req)
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("pinFileToIPFS 返回 status=%d: %s", resp.StatusCode, string(respBody))
+		}
+
+		var result struct {
+			IpfsHash string `json:"IpfsHash"`
+		}
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return fmt.Errorf("解析 pinFileToIPFS 响应失败: %w", err)
+		}
+		cid = result.IpfsHash
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("❌ 上传到 Pinata 失败: %w", err)
+	}
+
+	if onProgress != nil {
+		onProgress(targetPath, stat.Size(), stat.Size())
+	}
+	fmt.Println("✅ 上传成功!")
+	fmt.Printf("   - CID: %s\n", cid)
+	return cid, nil
+}
+
+// UploadJSON 通过 pinJSONToIPFS 上传元数据，并附带 pinataMetadata（name + keyvalues）。
+func (p *PinataUploader) UploadJSON(ctx context.Context, data NftMetadata) (string, error) {
+	fmt.Println("\n--- 正在上传 JSON 对象到 Pinata ---")
+
+	payload := struct {
+		PinataContent  NftMetadata    `json:"pinataContent"`
+		PinataMetadata pinataMetadata `json:"pinataMetadata"`
+	}{
+		PinataContent: data,
+		PinataMetadata: pinataMetadata{
+			Name: data.Name,
+			KeyValues: map[string]string{
+				"description": data.Description,
+			},
+		},
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("❌ 转换 JSON 失败: %w", err)
+	}
+
+	var cid string
+	err = withBackoff(p.maxRetries, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, pinataBaseURL+"/pinning/pinJSONToIPFS", bytes.NewReader(reqBody))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		p.authHeaders(req)
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("pinJSONToIPFS 返回 status=%d: %s", resp.StatusCode, string(respBody))
+		}
+
+		var result struct {
+			IpfsHash string `json:"IpfsHash"`
+		}
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return fmt.Errorf("解析 pinJSONToIPFS 响应失败: %w", err)
+		}
+		cid = result.IpfsHash
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("❌ 上传 JSON 到 Pinata 失败: %w", err)
+	}
+
+	fmt.Printf("✅ JSON 元数据上传成功!\n   - CID: %s\n", cid)
+	return cid, nil
+}
+
+type pinataMetadata struct {
+	Name      string            `json:"name"`
+	KeyValues map[string]string `json:"keyvalues"`
+}
+
+// buildPinFileBody 把 path 打包成 multipart/form-data，每个文件都带上
+// 相对于 path 自身（而不是 path 的父目录）的 filepath 字段——Pinata 会把这些
+// filepath 拼成一棵目录树，返回的 CID 就是这棵树的根；如果带上 path 自己的
+// 目录名作为前缀，根的直接子节点就会变成那一层目录，而不是文件本身，
+// 这样 ipfs://{CID}/{fileName} 这种寻址方式（与 Kubo 后端一致）就会 404。
+func buildPinFileBody(path string, stat os.FileInfo, rootName string) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	addFile := func(fsPath, archivePath string) error {
+		f, err := os.Open(fsPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		part, err := writer.CreateFormFile("file", archivePath)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f); err != nil {
+			return err
+		}
+		return writer.WriteField("filepath", archivePath)
+	}
+
+	if stat.IsDir() {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(path, p)
+			if err != nil {
+				return err
+			}
+			return addFile(p, rel)
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("❌ 打包目录失败: %w", err)
+		}
+	} else {
+		if err := addFile(path, rootName); err != nil {
+			return nil, "", fmt.Errorf("❌ 打包文件失败: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("❌ 关闭 multipart writer 失败: %w", err)
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// withBackoff 以指数退避重试 fn，最多尝试 maxRetries 次。
+func withBackoff(maxRetries int, fn func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := time.Duration(1<<uint(attempt-1)) * time.Second
+			fmt.Printf("   - 第 %d 次重试，等待 %s...\n", attempt, wait)
+			time.Sleep(wait)
+		}
+		if err := fn(); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
@@ -0,0 +1,226 @@
+// privatenetwork.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/coreapi"
+	"github.com/ipfs/kubo/plugin/loader"
+	"github.com/ipfs/kubo/repo/fsrepo"
+)
+
+// ErrPublicDHTDetected 是 VerifyPrivateNetwork 在 Enforce=true 时检测到目标节点
+// 仍连接着公网 DHT bootstrap 节点而明确拒绝运行的哨兵错误。它和其他错误
+// （比如单纯连不上目标节点的瞬时网络故障）要能被区分开：前者是策略拒绝，
+// 调用方不应该用 SpawnEphemeral 兜底把它悄悄吞掉。
+var ErrPublicDHTDetected = errors.New("目标节点连接着公网 DHT bootstrap 节点")
+
+// PrivateNetworkConfig 描述如何针对一个私有 IPFS swarm（而非公网 DHT）运行本工具。
+type PrivateNetworkConfig struct {
+	Enabled        bool     `json:"enabled"`
+	SwarmKeyPath   string   `json:"swarm_key_path"`   // swarm.key 文件路径
+	SwarmKeyInline string   `json:"swarm_key_inline"` // 直接内联 "/key/swarm/psk/1.0.0/..." 内容
+	BootstrapPeers []string `json:"bootstrap_peers"`  // 私有网络的 bootstrap multiaddr 列表
+	SpawnEphemeral bool     `json:"spawn_ephemeral"`  // 本地探测不到守护进程时，是否临时起一个 in-process 节点
+	Enforce        bool     `json:"enforce"`          // true 时，若目标节点疑似挂在公网 DHT 上则拒绝运行
+	GatewayHint    string   `json:"gateway_hint"`     // 例如 https://gateway.internal.example.com/ipfs/
+}
+
+// knownPublicBootstrapPeerIDs 是 Kubo 默认公网 bootstrap 列表里的 peer ID，
+// 用来粗略判断目标节点是否仍然挂在公网 DHT 上。
+var knownPublicBootstrapPeerIDs = []string{
+	"QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN",
+	"QmQCU2EcMqAqQPR2i9bChDtGNJchTbq5TbXJJ16u19uLTa",
+	"QmbLHAnMoJPWSCR5Zhtx6BHJX9KiKNN6tpvbUcqanj75Nb",
+	"QmcZf59bWwK5XFi76CZX8cbJ4BhTzzA3gU1ZjYZcYW3dwt",
+}
+
+// LoadSwarmKey 优先使用内联 PSK，其次从 SwarmKeyPath 读取。
+func LoadSwarmKey(cfg PrivateNetworkConfig) (string, error) {
+	if cfg.SwarmKeyInline != "" {
+		return cfg.SwarmKeyInline, nil
+	}
+	if cfg.SwarmKeyPath == "" {
+		return "", fmt.Errorf("❌ 私有网络配置缺少 swarm_key_path / swarm_key_inline")
+	}
+	raw, err := os.ReadFile(cfg.SwarmKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("❌ 读取 swarm.key 失败: %w", err)
+	}
+	return string(raw), nil
+}
+
+// swarmKeyFingerprint 返回 PSK 内容的 sha256 摘要，用于人工核对两端是否使用同一把 key，
+// 避免把 key 本身打印到日志里。
+func swarmKeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+type kuboIDResponse struct {
+	ID           string `json:"ID"`
+	AgentVersion string `json:"AgentVersion"`
+}
+
+type kuboSwarmPeersResponse struct {
+	Peers []struct {
+		Peer string `json:"Peer"`
+	} `json:"Peers"`
+}
+
+// VerifyPrivateNetwork 在启动时核对目标 Kubo 节点是否确实运行在私有 swarm 里：
+// 先打印本地 PSK 的指纹供人工核对（Kubo 的 HTTP API 并不会直接暴露对端的 PSK），
+// 再用 /api/v0/swarm/peers 检查是否出现了公网默认 bootstrap 节点的 peer ID——
+// 如果出现且 Enforce=true，则认为节点仍挂在公网 DHT 上，直接拒绝运行。
+func VerifyPrivateNetwork(ctx context.Context, apiURL string, cfg PrivateNetworkConfig) error {
+	key, err := LoadSwarmKey(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔑 私有网络 PSK 指纹: %s\n", swarmKeyFingerprint(key))
+
+	client := &http.Client{}
+
+	idResp, err := postJSON[kuboIDResponse](ctx, client, apiURL+"/api/v0/id")
+	if err != nil {
+		return fmt.Errorf("❌ 查询目标节点身份失败: %w", err)
+	}
+	fmt.Printf("🔎 目标节点: %s (%s)\n", idResp.ID, idResp.AgentVersion)
+
+	peersResp, err := postJSON[kuboSwarmPeersResponse](ctx, client, apiURL+"/api/v0/swarm/peers")
+	if err != nil {
+		return fmt.Errorf("❌ 查询目标节点 swarm peers 失败: %w", err)
+	}
+
+	for _, peer := range peersResp.Peers {
+		for _, publicID := range knownPublicBootstrapPeerIDs {
+			if strings.Contains(peer.Peer, publicID) {
+				if cfg.Enforce {
+					return fmt.Errorf("❌ 目标节点似乎仍连接着公网 DHT bootstrap 节点 (%s)，已拒绝运行 (PrivateNetwork.Enforce=true): %w", publicID, ErrPublicDHTDetected)
+				}
+				fmt.Printf("⚠️  目标节点连接着公网 bootstrap 节点 (%s)，但 Enforce=false，继续运行\n", publicID)
+			}
+		}
+	}
+
+	fmt.Println("✅ 私有网络检查通过")
+	return nil
+}
+
+// postJSON 向 Kubo RPC 的 POST 端点发起请求并解析 JSON 响应（Kubo 的 v0 API 约定用 POST）。
+func postJSON[T any](ctx context.Context, client *http.Client, url string) (T, error) {
+	var zero T
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return zero, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return zero, err
+	}
+	defer resp.Body.Close()
+
+	var result T
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return zero, err
+	}
+	return result, nil
+}
+
+// SpawnEphemeralNode 在找不到可用的外部 Kubo 守护进程时，
+// 用 boxo/kubo 的 core 包在进程内启动一个临时节点，仅用这一次运行的生命周期，
+// 并把私有网络的 PSK 和 bootstrap 节点接进去。调用方负责在用完后调用返回的 cleanup。
+func SpawnEphemeralNode(ctx context.Context, cfg PrivateNetworkConfig) (uploader *KuboUploader, cleanup func(), err error) {
+	key, err := LoadSwarmKey(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repoPath, err := os.MkdirTemp("", "polyglot-ipfs-uploader-ephemeral-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ 创建临时 repo 目录失败: %w", err)
+	}
+	removeRepo := func() { os.RemoveAll(repoPath) }
+
+	if err := fsrepo.Init(repoPath, nil); err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 初始化临时 repo 失败: %w", err)
+	}
+	if err := os.WriteFile(repoPath+"/swarm.key", []byte(key), 0600); err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 写入 swarm.key 失败: %w", err)
+	}
+
+	pluginLoader, err := loader.NewPluginLoader(repoPath)
+	if err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 加载 Kubo 插件失败: %w", err)
+	}
+	if err := pluginLoader.Initialize(); err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 初始化 Kubo 插件失败: %w", err)
+	}
+
+	repo, err := fsrepo.Open(repoPath)
+	if err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 打开临时 repo 失败: %w", err)
+	}
+
+	if len(cfg.BootstrapPeers) > 0 {
+		conf, err := repo.Config()
+		if err != nil {
+			removeRepo()
+			return nil, nil, fmt.Errorf("❌ 读取临时 repo 配置失败: %w", err)
+		}
+		conf.Bootstrap = cfg.BootstrapPeers
+		if err := repo.SetConfig(conf); err != nil {
+			removeRepo()
+			return nil, nil, fmt.Errorf("❌ 写入私有网络 bootstrap 节点失败: %w", err)
+		}
+	}
+
+	node, err := core.NewNode(ctx, &core.BuildCfg{
+		Repo:   repo,
+		Online: true,
+	})
+	if err != nil {
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 启动 in-process 节点失败: %w", err)
+	}
+
+	api, err := coreapi.NewCoreAPI(node)
+	if err != nil {
+		node.Close()
+		removeRepo()
+		return nil, nil, fmt.Errorf("❌ 构造 CoreAPI 失败: %w", err)
+	}
+
+	fmt.Printf("✅ 已启动临时 in-process 节点 (PSK 指纹: %s)\n", swarmKeyFingerprint(key))
+
+	cleanup = func() {
+		node.Close()
+		removeRepo()
+	}
+	return NewKuboUploaderFromAPI(api), cleanup, nil
+}
+
+// RewriteGatewayURI 把 ipfs://{cid} 重写为私有网络的网关地址，
+// 这样发布出去的 URI 在没有直连私有 swarm 的客户端上也能访问。
+func RewriteGatewayURI(cfg PrivateNetworkConfig, ipfsURI string) string {
+	if !cfg.Enabled || cfg.GatewayHint == "" {
+		return ipfsURI
+	}
+	rest := strings.TrimPrefix(ipfsURI, "ipfs://")
+	hint := strings.TrimSuffix(cfg.GatewayHint, "/")
+	return fmt.Sprintf("%s/%s", hint, rest)
+}
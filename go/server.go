@@ -0,0 +1,308 @@
+// server.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// HTTPServerConfig 控制 `polyglot-ipfs-uploader serve` 暴露的 HTTP API。
+type HTTPServerConfig struct {
+	Addr              string   `json:"addr"`               // 监听地址，例如 ":8080"
+	JWTSecret         string   `json:"-"`                  // 来自 HTTP_JWT_SECRET 环境变量
+	AllowedExtensions []string `json:"allowed_extensions"` // 上传文件扩展名白名单
+	ImageMaxSizeMB    int      `json:"image_max_size_mb"`  // 单张图片大小上限 (MB)
+}
+
+// DefaultHTTPServerConfig 给出默认配置：监听 :8080，图片格式白名单 .png/.jpg/.jpeg/.gif，
+// 单张上限 10 MB。
+func DefaultHTTPServerConfig() HTTPServerConfig {
+	return HTTPServerConfig{
+		Addr:              ":8080",
+		AllowedExtensions: []string{".png", ".jpg", ".jpeg", ".gif"},
+		ImageMaxSizeMB:    10,
+	}
+}
+
+// runServeCommand 解析 `serve` 子命令自己的 flag，启动 HTTP 服务器。
+// 这是一个独立的 flag.FlagSet，不与根命令的 flag 混在一起。
+func runServeCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "", "配置文件路径 (JSON)")
+	addr := fs.String("addr", "", "监听地址，覆盖配置文件里的 http_server.addr")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *addr != "" {
+		cfg.HTTPServer.Addr = *addr
+	}
+	cfg.HTTPServer.JWTSecret = os.Getenv("HTTP_JWT_SECRET")
+	if cfg.HTTPServer.JWTSecret == "" {
+		fmt.Println("❌ 缺少 HTTP_JWT_SECRET 环境变量，无法启动受 JWT 保护的 HTTP API")
+		os.Exit(1)
+	}
+
+	router := newRouter(cfg)
+	fmt.Printf("✅ HTTP API 正在监听 %s\n", cfg.HTTPServer.Addr)
+	if err := router.Run(cfg.HTTPServer.Addr); err != nil {
+		fmt.Printf("❌ HTTP 服务器退出: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRouter(cfg *Config) *gin.Engine {
+	router := gin.Default()
+	pool := newUploaderPool(cfg)
+
+	api := router.Group("/")
+	api.Use(jwtAuthMiddleware(cfg.HTTPServer.JWTSecret))
+	api.POST("/upload/single", handleUploadSingle(cfg, pool))
+	api.POST("/upload/batch", handleUploadBatch(cfg, pool))
+
+	return router
+}
+
+// uploaderPool 按 backend 缓存已经建好的 Uploader，整个服务进程共用、复用，
+// 避免每个请求都重新构造一次——对 Kubo + SpawnEphemeral 来说，
+// 这意味着每个请求都要重新起一个 in-process 节点，代价高到服务扛不住任何并发。
+type uploaderPool struct {
+	mu        sync.Mutex
+	cfg       *Config
+	uploaders map[string]Uploader
+}
+
+func newUploaderPool(cfg *Config) *uploaderPool {
+	return &uploaderPool{cfg: cfg, uploaders: make(map[string]Uploader)}
+}
+
+// get 返回 backend 对应的 Uploader，首次用到时才构造，此后一直复用同一个实例。
+// backend 为空字符串时使用 cfg 里的默认 backend。
+func (p *uploaderPool) get(backend string) (Uploader, error) {
+	if backend == "" {
+		backend = p.cfg.Backend
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if uploader, ok := p.uploaders[backend]; ok {
+		return uploader, nil
+	}
+
+	reqCfg := *p.cfg
+	reqCfg.Backend = backend
+	uploader, _, err := NewUploader(&reqCfg)
+	if err != nil {
+		return nil, err
+	}
+	p.uploaders[backend] = uploader
+	return uploader, nil
+}
+
+// jwtAuthMiddleware 要求请求带上 `Authorization: Bearer <token>`，
+// 用共享密钥校验签名，这样本服务可以直接接进已有的 mint dApp 后端。
+func jwtAuthMiddleware(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+		if tokenStr == "" || tokenStr == authHeader {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "缺少 Bearer token"})
+			return
+		}
+
+		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err != nil || !token.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的 token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// resolveUploaderFromRequest 根据 X-Pin-Service 头选择本次请求要用的 Pinning 后端，
+// 不传则沿用 config.json 里的默认 backend；Uploader 本身来自 pool，按 backend 复用。
+func resolveUploaderFromRequest(c *gin.Context, pool *uploaderPool) (Uploader, error) {
+	return pool.get(c.GetHeader("X-Pin-Service"))
+}
+
+// validateUploadedImage 校验扩展名白名单和体积上限，在真正触发上传前就把错误请求挡掉。
+func validateUploadedImage(cfg *Config, filename string, sizeBytes int64) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	allowed := false
+	for _, a := range cfg.HTTPServer.AllowedExtensions {
+		if ext == a {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("不支持的文件类型: %s（允许: %s）", ext, strings.Join(cfg.HTTPServer.AllowedExtensions, ", "))
+	}
+
+	maxBytes := int64(cfg.HTTPServer.ImageMaxSizeMB) * 1024 * 1024
+	if sizeBytes > maxBytes {
+		return fmt.Errorf("文件过大: %d bytes（上限 %d MB）", sizeBytes, cfg.HTTPServer.ImageMaxSizeMB)
+	}
+	return nil
+}
+
+// handleUploadSingle 对应 POST /upload/single，接收一个 multipart 文件，
+// 返回 {imageCid, metadataCid, tokenURI, metadata}。
+func handleUploadSingle(cfg *Config, pool *uploaderPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 file 字段"})
+			return
+		}
+		if err := validateUploadedImage(cfg, fileHeader.Filename, fileHeader.Size); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tmpPath, cleanupFile, err := saveUploadedFileToTemp(fileHeader)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer cleanupFile()
+
+		uploader, err := resolveUploaderFromRequest(c, pool)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		opts := WorkflowOptions{
+			Processor:  NewImageProcessor(cfg.ImageProcessor),
+			PrivateNet: cfg.PrivateNetwork,
+			IPFSAPIURL: cfg.IPFSAPIURL,
+		}
+
+		result, err := UploadSingleNFT(c.Request.Context(), uploader, opts, tmpPath)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// handleUploadBatch 对应 POST /upload/batch，接收多个 multipart 文件，
+// 每个文件一完成 pinning 就往响应里写一行 NDJSON 进度，而不是等全部完成再一次性返回。
+func handleUploadBatch(cfg *Config, pool *uploaderPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 multipart 表单"})
+			return
+		}
+		fileHeaders := form.File["files"]
+		if len(fileHeaders) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 files 字段"})
+			return
+		}
+
+		uploader, err := resolveUploaderFromRequest(c, pool)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		opts := WorkflowOptions{
+			Processor:  NewImageProcessor(cfg.ImageProcessor),
+			PrivateNet: cfg.PrivateNetwork,
+			IPFSAPIURL: cfg.IPFSAPIURL,
+		}
+
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		flusher, canFlush := c.Writer.(http.Flusher)
+
+		for _, fileHeader := range fileHeaders {
+			event := batchProgressEvent{Filename: fileHeader.Filename}
+
+			if err := validateUploadedImage(cfg, fileHeader.Filename, fileHeader.Size); err != nil {
+				event.Error = err.Error()
+				encoder.Encode(event)
+				if canFlush {
+					flusher.Flush()
+				}
+				continue
+			}
+
+			tmpPath, cleanupFile, err := saveUploadedFileToTemp(fileHeader)
+			if err != nil {
+				event.Error = err.Error()
+				encoder.Encode(event)
+				if canFlush {
+					flusher.Flush()
+				}
+				continue
+			}
+
+			result, err := UploadSingleNFT(c.Request.Context(), uploader, opts, tmpPath)
+			cleanupFile()
+			if err != nil {
+				event.Error = err.Error()
+			} else {
+				event.Result = &result
+			}
+			encoder.Encode(event)
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// batchProgressEvent 是 /upload/batch 响应流里的单行 NDJSON。
+type batchProgressEvent struct {
+	Filename string           `json:"filename"`
+	Result   *SingleNFTResult `json:"result,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// saveUploadedFileToTemp 把上传的 multipart 文件落到本地临时文件，
+// 因为 Uploader.UploadPath 和 ImageProcessor.Process 走的都是本地路径而不是 io.Reader。
+func saveUploadedFileToTemp(fileHeader *multipart.FileHeader) (string, func(), error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("❌ 打开上传文件失败: %w", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "upload-*"+filepath.Ext(fileHeader.Filename))
+	if err != nil {
+		return "", nil, fmt.Errorf("❌ 创建临时文件失败: %w", err)
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, fmt.Errorf("❌ 保存上传文件失败: %w", err)
+	}
+
+	cleanup := func() { os.Remove(tmp.Name()) }
+	return tmp.Name(), cleanup, nil
+}
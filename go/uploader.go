@@ -0,0 +1,85 @@
+// uploader.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ProgressFunc 用于汇报上传进度，name 是文件路径或整个上传路径。
+// done/total 以字节为单位；total 为 0 表示总大小未知（例如流式上传）。
+type ProgressFunc func(name string, done, total int64)
+
+// LogProgress 是最简单的 ProgressFunc 实现：把每次回调打印到标准输出。
+// ClusterUploader 上传目录时对目录里的每个文件各回调一次；KuboUploader 和
+// PinataUploader 只在整个 targetPath 上传完成后回调一次（name 为 targetPath），
+// 不提供逐文件进度。
+func LogProgress(name string, done, total int64) {
+	if total > 0 {
+		fmt.Printf("   ↳ %s: %d/%d bytes\n", name, done, total)
+	} else {
+		fmt.Printf("   ↳ %s: %d bytes\n", name, done)
+	}
+}
+
+// Uploader 把"把一份内容发布到 IPFS"这件事抽象出来，
+// 这样本地 Kubo 节点和 Pinata、IPFS Cluster 这类远程 Pinning 服务
+// 才能被 processSingleNFT / processBatchCollection 以同样的方式调用。
+type Uploader interface {
+	// UploadPath 上传本地文件或目录，返回根 CID。
+	UploadPath(ctx context.Context, targetPath string, onProgress ProgressFunc) (string, error)
+	// UploadJSON 上传一份 NFT 元数据，返回其 CID。
+	UploadJSON(ctx context.Context, data NftMetadata) (string, error)
+	// Name 返回 backend 的标识，用于日志。
+	Name() string
+}
+
+// BackendKind 枚举所有支持的 Pinning 后端，驱动配置文件 / CLI flag 的选择。
+type BackendKind string
+
+const (
+	BackendKubo    BackendKind = "kubo"
+	BackendPinata  BackendKind = "pinata"
+	BackendCluster BackendKind = "cluster"
+)
+
+// NewUploader 根据配置里的 backend 字段构造对应的 Uploader 实现。
+// 返回的 cleanup 在 PrivateNetwork.SpawnEphemeral 启动了 in-process 节点时
+// 负责关闭节点、清理临时 repo；其余情况下是空操作。
+func NewUploader(cfg *Config) (uploader Uploader, cleanup func(), err error) {
+	noop := func() {}
+
+	if BackendKind(cfg.Backend) == BackendKubo || cfg.Backend == "" {
+		if cfg.PrivateNetwork.Enabled {
+			ctx := context.Background()
+			verifyErr := VerifyPrivateNetwork(ctx, cfg.IPFSAPIURL, cfg.PrivateNetwork)
+			if verifyErr != nil && errors.Is(verifyErr, ErrPublicDHTDetected) {
+				// Enforce=true 下的策略拒绝：不能用 SpawnEphemeral 兜底吞掉，必须直接拒绝运行。
+				return nil, noop, verifyErr
+			}
+			if verifyErr != nil && cfg.PrivateNetwork.SpawnEphemeral {
+				fmt.Printf("⚠️  无法核实外部节点 (%v)，改为启动临时 in-process 节点\n", verifyErr)
+				u, cleanup, err := SpawnEphemeralNode(ctx, cfg.PrivateNetwork)
+				return u, cleanup, err
+			}
+			if verifyErr != nil {
+				return nil, noop, verifyErr
+			}
+		}
+	}
+
+	switch BackendKind(cfg.Backend) {
+	case BackendKubo, "":
+		u, err := NewKuboUploader(cfg.IPFSAPIURL)
+		return u, noop, err
+	case BackendPinata:
+		u, err := NewPinataUploader(cfg.Pinata)
+		return u, noop, err
+	case BackendCluster:
+		u, err := NewClusterUploader(cfg.Cluster)
+		return u, noop, err
+	default:
+		return nil, noop, errUnknownBackend(cfg.Backend)
+	}
+}